@@ -0,0 +1,139 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Non-interactive execution: -f <script>, piped stdin (no flag needed when
+// stdin isn't a TTY), and --batch all funnel through runBatch. Scripts can
+// mix ordinary commands with a few control directives — sleep, wait-for,
+// and assert — so a login-then-check flow doesn't need a wrapper shell
+// script around individual -c invocations.
+
+func stdinIsPipe() bool {
+	fi, err := os.Stdin.Stat()
+	if err != nil {
+		return false
+	}
+	return fi.Mode()&os.ModeCharDevice == 0
+}
+
+// runBatch executes one command (or directive) per line from r, returning
+// the process exit code: 0 if everything succeeded, 1 if anything failed
+// (including a scanner error reading r itself). Without continueOnError it
+// stops at the first failing line; with it, the rest of the script still
+// runs and the exit code is still 1.
+func runBatch(r io.Reader, continueOnError bool) int {
+	exitCode := 0
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		if err := dispatchDirective(line); err != nil {
+			out("%serror:%s %v", cRed, cReset, err)
+			if exitCode == 0 {
+				exitCode = 1
+			}
+			if !continueOnError {
+				break
+			}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		out("%serror:%s reading script: %v", cRed, cReset, err)
+		exitCode = 1
+	}
+	return exitCode
+}
+
+// dispatchDirective handles the small control-flow vocabulary scripts get
+// on top of ordinary dispatched commands.
+func dispatchDirective(line string) error {
+	fields := strings.Fields(line)
+	switch fields[0] {
+	case "sleep":
+		if len(fields) < 2 {
+			return fmt.Errorf("usage: sleep <duration>")
+		}
+		d, err := time.ParseDuration(fields[1])
+		if err != nil {
+			return fmt.Errorf("invalid sleep duration: %w", err)
+		}
+		time.Sleep(d)
+		return nil
+
+	case "wait-for":
+		if len(fields) < 2 {
+			return fmt.Errorf("usage: wait-for <event>")
+		}
+		return waitForEvent(fields[1], cmdTimeout)
+
+	case "assert":
+		return runAssert(strings.TrimPrefix(line, "assert "))
+
+	default:
+		return dispatchChecked(line)
+	}
+}
+
+// runAssert checks a ${result:...}-style dotted path (without the
+// ${result: ... } wrapper) against an optional expected value:
+//
+//	assert tabs.list.0.url == "https://example.com"
+//	assert dom.querySelector.tag
+func runAssert(expr string) error {
+	parts := strings.SplitN(expr, "==", 2)
+	path := strings.TrimSpace(parts[0])
+	got := lookupResult(path)
+
+	if len(parts) == 2 {
+		want := strings.Trim(strings.TrimSpace(parts[1]), `"'`)
+		if got != want {
+			return fmt.Errorf("assert failed: %s == %q, got %q", path, want, got)
+		}
+		return nil
+	}
+	if got == "" || got == "false" || got == "null" {
+		return fmt.Errorf("assert failed: %s is falsy (got %q)", path, got)
+	}
+	return nil
+}
+
+// --- wait-for: a minimal one-shot event wait, independent of .events display ---
+
+var (
+	eventWaitersMu sync.Mutex
+	eventWaiters   = map[string][]chan struct{}{}
+)
+
+func notifyEventWaiters(name string) {
+	eventWaitersMu.Lock()
+	waiters := eventWaiters[name]
+	delete(eventWaiters, name)
+	eventWaitersMu.Unlock()
+	for _, ch := range waiters {
+		close(ch)
+	}
+}
+
+func waitForEvent(name string, timeout time.Duration) error {
+	ch := make(chan struct{})
+	eventWaitersMu.Lock()
+	eventWaiters[name] = append(eventWaiters[name], ch)
+	eventWaitersMu.Unlock()
+
+	select {
+	case <-ch:
+		return nil
+	case <-time.After(timeout):
+		return fmt.Errorf("timed out waiting for event %q", name)
+	}
+}
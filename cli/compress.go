@@ -0,0 +1,89 @@
+package main
+
+import (
+	"strings"
+	"sync/atomic"
+
+	"github.com/gorilla/websocket"
+)
+
+// permessage-deflate (RFC 7692): gorilla/websocket already does the frame
+// compression and buffer pooling for us once the extension is negotiated —
+// we just need to ask for it, and forward what the server agreed to back to
+// the connection. --compress=auto lets the handshake negotiate normally;
+// on/off force the client's offer (and, for off, also disable write
+// compression if the server would otherwise turn it on).
+var (
+	compressMode  = "auto" // --compress: auto|on|off
+	compressLevel = 0      // --compress-level: 0 means "library default"
+
+	// negotiatedCompression and negotiatedNoContextTakeover record the
+	// outcome of the last handshake, for `.status` to report. 0/1 in place
+	// of bool so they can be read and written with the atomic package, the
+	// same pattern showEvents already uses.
+	negotiatedCompression       int32
+	negotiatedNoContextTakeover int32
+)
+
+func compressionNegotiated() bool {
+	return atomic.LoadInt32(&negotiatedCompression) != 0
+}
+
+func compressionNoContextTakeover() bool {
+	return atomic.LoadInt32(&negotiatedNoContextTakeover) != 0
+}
+
+func storeBool(dst *int32, v bool) {
+	if v {
+		atomic.StoreInt32(dst, 1)
+	} else {
+		atomic.StoreInt32(dst, 0)
+	}
+}
+
+// parseCompressionExtension reports whether the server's negotiated
+// Sec-WebSocket-Extensions header includes permessage-deflate, along with
+// the context-takeover flags it asked for.
+func parseCompressionExtension(header string) (negotiated bool, serverNoContextTakeover bool, clientNoContextTakeover bool) {
+	for _, ext := range strings.Split(header, ",") {
+		params := strings.Split(ext, ";")
+		if strings.TrimSpace(params[0]) != "permessage-deflate" {
+			continue
+		}
+		negotiated = true
+		for _, p := range params[1:] {
+			switch strings.TrimSpace(p) {
+			case "server_no_context_takeover":
+				serverNoContextTakeover = true
+			case "client_no_context_takeover":
+				clientNoContextTakeover = true
+			}
+		}
+	}
+	return
+}
+
+// applyCompression configures the dialer's offer; finishCompressionNegotiation
+// reconciles what the server actually agreed to once the handshake returns.
+func applyCompression(dialer *websocket.Dialer) {
+	dialer.EnableCompression = compressMode != "off"
+}
+
+func finishCompressionNegotiation(c *websocket.Conn, extHeader string) {
+	negotiated, serverNoTakeover, clientNoTakeover := parseCompressionExtension(extHeader)
+	storeBool(&negotiatedCompression, negotiated)
+	storeBool(&negotiatedNoContextTakeover, serverNoTakeover || clientNoTakeover)
+	if !negotiated {
+		return
+	}
+
+	switch compressMode {
+	case "off":
+		c.EnableWriteCompression(false)
+	default:
+		c.EnableWriteCompression(true)
+		if compressLevel != 0 {
+			c.SetCompressionLevel(compressLevel)
+		}
+	}
+}
@@ -0,0 +1,350 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Cookie jar import/export. `cookies load <file>` auto-detects Netscape
+// cookies.txt, EditThisCookie/Cookie-Editor JSON exports, and the Chrome
+// DevTools Network.getAllCookies shape on top of the plain JSON array this
+// client already wrote. `cookies save <file> [format]` does the reverse.
+
+type cookieFormat string
+
+const (
+	fmtRaw       cookieFormat = "json"      // internal: array of cookies.set params
+	fmtNetscape  cookieFormat = "netscape"  // cookies.txt (curl/wget)
+	fmtExtension cookieFormat = "extension" // EditThisCookie / Cookie-Editor
+	fmtDevtools  cookieFormat = "devtools"  // CDP Network.getAllCookies
+)
+
+// detectCookieFormat sniffs the file header to pick a parser.
+func detectCookieFormat(data []byte) cookieFormat {
+	trimmed := bytes.TrimSpace(data)
+	head := trimmed
+	if len(head) > 64 {
+		head = head[:64]
+	}
+	if bytes.HasPrefix(trimmed, []byte("#")) || bytes.Contains(head, []byte("Netscape")) {
+		return fmtNetscape
+	}
+
+	var probe interface{}
+	if json.Unmarshal(trimmed, &probe) != nil {
+		return fmtNetscape // not JSON at all; let the netscape parser report the real error
+	}
+	switch v := probe.(type) {
+	case map[string]interface{}:
+		if _, ok := v["cookies"]; ok {
+			return fmtDevtools
+		}
+	case []interface{}:
+		if len(v) > 0 {
+			if obj, ok := v[0].(map[string]interface{}); ok {
+				if _, ok := obj["hostOnly"]; ok {
+					return fmtExtension
+				}
+				if _, ok := obj["storeId"]; ok {
+					return fmtExtension
+				}
+			}
+		}
+	}
+	return fmtRaw
+}
+
+func canonicalizeSameSite(s string) string {
+	switch strings.ToLower(s) {
+	case "strict":
+		return "Strict"
+	case "lax":
+		return "Lax"
+	case "none", "no_restriction":
+		return "None"
+	default:
+		return ""
+	}
+}
+
+// normalizeCookie maps a loosely-typed cookie object (from any supported
+// import format) into the params this client's cookies.set action expects.
+func normalizeCookie(c map[string]interface{}) map[string]interface{} {
+	norm := map[string]interface{}{}
+	if v, ok := c["name"]; ok {
+		norm["name"] = v
+	}
+	if v, ok := c["value"]; ok {
+		norm["value"] = v
+	}
+	if v, ok := c["path"]; ok && v != "" {
+		norm["path"] = v
+	} else {
+		norm["path"] = "/"
+	}
+
+	hostOnly, _ := c["hostOnly"].(bool)
+	if domain, ok := c["domain"].(string); ok && domain != "" {
+		if hostOnly {
+			norm["domain"] = strings.TrimPrefix(domain, ".")
+		} else {
+			norm["domain"] = domain
+		}
+	}
+
+	if exp, ok := c["expirationDate"]; ok {
+		norm["expires"] = exp
+	} else if exp, ok := c["expires"]; ok {
+		norm["expires"] = exp
+	}
+
+	if ss, ok := c["sameSite"].(string); ok {
+		if canon := canonicalizeSameSite(ss); canon != "" {
+			norm["sameSite"] = canon
+		}
+	}
+
+	if v, ok := c["secure"]; ok {
+		norm["secure"] = v
+	}
+	if v, ok := c["httpOnly"]; ok {
+		norm["httpOnly"] = v
+	}
+	return norm
+}
+
+// parseNetscape reads a curl/wget-style cookies.txt. If the file has any
+// non-comment content but none of it parses as a valid cookie line, that's
+// almost always a garbled/wrong-format file rather than an empty jar, so we
+// report it as an error instead of silently returning zero cookies.
+func parseNetscape(data []byte) ([]map[string]interface{}, error) {
+	var cookies []map[string]interface{}
+	attempted := 0
+	for _, line := range strings.Split(string(data), "\n") {
+		line = strings.TrimRight(line, "\r")
+		if line == "" {
+			continue
+		}
+		httpOnly := false
+		if strings.HasPrefix(line, "#HttpOnly_") {
+			httpOnly = true
+			line = strings.TrimPrefix(line, "#HttpOnly_")
+		} else if strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		attempted++
+		parts := strings.Split(line, "\t")
+		if len(parts) < 7 {
+			continue
+		}
+		includeSubdomains := parts[1] == "TRUE"
+		secure := parts[3] == "TRUE"
+		expiration, _ := strconv.ParseInt(parts[4], 10, 64)
+
+		cookies = append(cookies, map[string]interface{}{
+			"domain":         parts[0],
+			"hostOnly":       !includeSubdomains,
+			"path":           parts[2],
+			"secure":         secure,
+			"httpOnly":       httpOnly,
+			"expirationDate": expiration,
+			"name":           parts[5],
+			"value":          parts[6],
+		})
+	}
+	if attempted > 0 && len(cookies) == 0 {
+		return nil, fmt.Errorf("no valid Netscape cookie lines found (expected 7 tab-separated fields per line)")
+	}
+	return cookies, nil
+}
+
+func parseCookieFile(data []byte, format cookieFormat) ([]map[string]interface{}, error) {
+	switch format {
+	case fmtNetscape:
+		return parseNetscape(data)
+	case fmtDevtools:
+		var wrapped struct {
+			Cookies []map[string]interface{} `json:"cookies"`
+		}
+		if err := json.Unmarshal(data, &wrapped); err != nil {
+			return nil, fmt.Errorf("invalid devtools JSON: %w", err)
+		}
+		return wrapped.Cookies, nil
+	default: // fmtRaw, fmtExtension — both are a plain JSON array of cookie objects
+		var cookies []map[string]interface{}
+		if err := json.Unmarshal(data, &cookies); err != nil {
+			return nil, fmt.Errorf("invalid JSON: %w", err)
+		}
+		return cookies, nil
+	}
+}
+
+// loadCookies: read a cookie file in any supported format and inject each
+// cookie via cookies.set.
+func loadCookies(args string) {
+	parts := strings.Fields(args)
+	file := "cookies.json"
+	if len(parts) > 1 {
+		file = parts[1]
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		out("%serror:%s %v", cRed, cReset, err)
+		return
+	}
+
+	format := detectCookieFormat(data)
+	cookies, err := parseCookieFile(data, format)
+	if err != nil {
+		out("%serror:%s %v", cRed, cReset, err)
+		return
+	}
+
+	ok, fail := 0, 0
+	for _, c := range cookies {
+		if _, err := sendAndWait("cookies.set", map[string]interface{}{"cookie": normalizeCookie(c)}); err != nil {
+			fail++
+		} else {
+			ok++
+		}
+	}
+	out("%s%d cookies loaded%s (%s), %d failed", cGreen, ok, cReset, format, fail)
+}
+
+// --- export ---
+
+func writeNetscape(path string, cookies []map[string]interface{}) error {
+	var b strings.Builder
+	b.WriteString("# Netscape HTTP Cookie File\n")
+	for _, c := range cookies {
+		domain, _ := c["domain"].(string)
+		includeSubdomains := strings.HasPrefix(domain, ".")
+		cpath, _ := c["path"].(string)
+		if cpath == "" {
+			cpath = "/"
+		}
+		secure, _ := c["secure"].(bool)
+		name, _ := c["name"].(string)
+		value, _ := c["value"].(string)
+		httpOnly, _ := c["httpOnly"].(bool)
+
+		expiration := int64(0)
+		switch e := c["expires"].(type) {
+		case float64:
+			if e > 0 {
+				expiration = int64(e)
+			}
+		}
+
+		prefix := ""
+		if httpOnly {
+			prefix = "#HttpOnly_"
+		}
+		fmt.Fprintf(&b, "%s%s\t%s\t%s\t%s\t%d\t%s\t%s\n",
+			prefix, domain, boolFlag(includeSubdomains), cpath, boolFlag(secure), expiration, name, value)
+	}
+	return os.WriteFile(path, []byte(b.String()), 0644)
+}
+
+func boolFlag(v bool) string {
+	if v {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+func writeExtensionJSON(path string, cookies []map[string]interface{}) error {
+	var out []map[string]interface{}
+	for _, c := range cookies {
+		domain, _ := c["domain"].(string)
+		entry := map[string]interface{}{
+			"domain":         domain,
+			"hostOnly":       !strings.HasPrefix(domain, "."),
+			"httpOnly":       c["httpOnly"],
+			"name":           c["name"],
+			"path":           c["path"],
+			"secure":         c["secure"],
+			"session":        c["session"],
+			"storeId":        "0",
+			"value":          c["value"],
+			"expirationDate": c["expires"],
+		}
+		if ss, ok := c["sameSite"].(string); ok {
+			entry["sameSite"] = strings.ToLower(ss)
+		}
+		out = append(out, entry)
+	}
+	data, err := json.MarshalIndent(out, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func writeDevtoolsJSON(path string, cookies []map[string]interface{}) error {
+	data, err := json.MarshalIndent(map[string]interface{}{"cookies": cookies}, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+func writeRawJSON(path string, cookies []map[string]interface{}) error {
+	data, err := json.MarshalIndent(cookies, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// saveCookies: "cookies save <file> [format]" — pulls cookies.getAll and
+// writes it out in the requested format (default: raw JSON array).
+func saveCookies(args string) {
+	parts := strings.Fields(args)
+	if len(parts) < 2 {
+		out("%susage: cookies save <file> [json|netscape|extension|devtools]%s", cDim, cReset)
+		return
+	}
+	file := parts[1]
+	format := fmtRaw
+	if len(parts) > 2 {
+		format = cookieFormat(parts[2])
+	}
+
+	result, err := sendAndWait("cookies.getAll", map[string]interface{}{})
+	if err != nil {
+		out("%serror:%s %v", cRed, cReset, err)
+		return
+	}
+	var cookies []map[string]interface{}
+	if err := json.Unmarshal(result, &cookies); err != nil {
+		out("%serror:%s %v", cRed, cReset, err)
+		return
+	}
+
+	var writeErr error
+	switch format {
+	case fmtNetscape:
+		writeErr = writeNetscape(file, cookies)
+	case fmtExtension:
+		writeErr = writeExtensionJSON(file, cookies)
+	case fmtDevtools:
+		writeErr = writeDevtoolsJSON(file, cookies)
+	case fmtRaw:
+		writeErr = writeRawJSON(file, cookies)
+	default:
+		out("%sunknown format:%s %s %s(want json|netscape|extension|devtools)%s", cRed, cReset, format, cDim, cReset)
+		return
+	}
+	if writeErr != nil {
+		out("%serror:%s %v", cRed, cReset, writeErr)
+		return
+	}
+	out("%s%d cookies saved%s (%s) -> %s", cGreen, len(cookies), cReset, format, file)
+}
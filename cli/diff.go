@@ -0,0 +1,159 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+)
+
+// runDiffCmd implements `hb diff a.jsonl b.jsonl`: it pairs up the two
+// sessions' recorded responses by their request id and reports payload
+// deltas — handy when a server upgrade changes a response shape and a
+// recording from before and after is on hand. Async event frames (no
+// matching request id) are ignored so they can't shift an unrelated
+// response out of alignment.
+func runDiffCmd(args []string) {
+	if len(args) != 2 {
+		fmt.Fprintln(os.Stderr, "usage: hb diff <a.jsonl> <b.jsonl>")
+		os.Exit(1)
+	}
+
+	a, err := loadSessionLog(args[0])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	b, err := loadSessionLog(args[1])
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+
+	respA := responsesByID(a)
+	respB := responsesByID(b)
+
+	seen := map[string]bool{}
+	var ids []string
+	for id := range respA {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	for id := range respB {
+		if !seen[id] {
+			seen[id] = true
+			ids = append(ids, id)
+		}
+	}
+	sort.Strings(ids)
+
+	diffCount := 0
+	for _, id := range ids {
+		rawA, haveA := respA[id]
+		rawB, haveB := respB[id]
+
+		switch {
+		case haveA && !haveB:
+			fmt.Printf("id %s: only in %s\n", id, args[0])
+			diffCount++
+		case !haveA && haveB:
+			fmt.Printf("id %s: only in %s\n", id, args[1])
+			diffCount++
+		default:
+			var va, vb interface{}
+			json.Unmarshal(rawA, &va)
+			json.Unmarshal(rawB, &vb)
+			if deltas := diffJSON("", va, vb); len(deltas) > 0 {
+				fmt.Printf("id %s:\n", id)
+				for _, d := range deltas {
+					fmt.Printf("  %s\n", d)
+				}
+				diffCount++
+			}
+		}
+	}
+
+	if diffCount == 0 {
+		fmt.Println("no differences")
+	}
+}
+
+// responsesByID collects the "recv" frames that are actual protocol
+// responses (they carry an "id" the request matched), keyed by that id.
+// Async event frames (no "id") are skipped so they can't shift later
+// comparisons out of alignment.
+func responsesByID(frames []sessionFrame) map[string]json.RawMessage {
+	out := map[string]json.RawMessage{}
+	for _, fr := range frames {
+		if fr.Dir != "recv" {
+			continue
+		}
+		var envelope struct {
+			ID string `json:"id"`
+		}
+		if json.Unmarshal(fr.Payload, &envelope) != nil || envelope.ID == "" {
+			continue
+		}
+		out[envelope.ID] = fr.Payload
+	}
+	return out
+}
+
+// diffJSON walks two decoded JSON values in parallel and returns a flat list
+// of "path: a != b" style differences.
+func diffJSON(path string, a, b interface{}) []string {
+	var deltas []string
+
+	am, aIsMap := a.(map[string]interface{})
+	bm, bIsMap := b.(map[string]interface{})
+	if aIsMap && bIsMap {
+		keys := map[string]bool{}
+		for k := range am {
+			keys[k] = true
+		}
+		for k := range bm {
+			keys[k] = true
+		}
+		sorted := make([]string, 0, len(keys))
+		for k := range keys {
+			sorted = append(sorted, k)
+		}
+		sort.Strings(sorted)
+		for _, k := range sorted {
+			childPath := k
+			if path != "" {
+				childPath = path + "." + k
+			}
+			av, aok := am[k]
+			bv, bok := bm[k]
+			switch {
+			case aok && !bok:
+				deltas = append(deltas, fmt.Sprintf("%s: removed (was %v)", childPath, av))
+			case !aok && bok:
+				deltas = append(deltas, fmt.Sprintf("%s: added (%v)", childPath, bv))
+			default:
+				deltas = append(deltas, diffJSON(childPath, av, bv)...)
+			}
+		}
+		return deltas
+	}
+
+	aa, aIsSlice := a.([]interface{})
+	bb, bIsSlice := b.([]interface{})
+	if aIsSlice && bIsSlice {
+		if len(aa) != len(bb) {
+			deltas = append(deltas, fmt.Sprintf("%s: length %d != %d", path, len(aa), len(bb)))
+		}
+		for i := 0; i < len(aa) && i < len(bb); i++ {
+			deltas = append(deltas, diffJSON(fmt.Sprintf("%s[%d]", path, i), aa[i], bb[i])...)
+		}
+		return deltas
+	}
+
+	if fmt.Sprintf("%v", a) != fmt.Sprintf("%v", b) {
+		deltas = append(deltas, fmt.Sprintf("%s: %v != %v", path, a, b))
+	}
+	return deltas
+}
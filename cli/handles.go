@@ -0,0 +1,113 @@
+package main
+
+import (
+	"encoding/json"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// handleStore promotes the ad-hoc el_N handles returned by
+// dom.discoverElements/doQuery into a first-class registry, so a long
+// interactive session has a stable frame of reference: `.handles` lists
+// what's known, `.handle <id>` re-prints it, and the readline completer
+// offers known ids after `click`/`box`.
+
+type handleEntry struct {
+	Info     elInfo
+	Selector string
+	TabID    int64
+	Seen     time.Time
+}
+
+var (
+	handleStore   = map[string]handleEntry{}
+	handleStoreMu sync.Mutex
+)
+
+func registerHandle(id, selector string, info elInfo) {
+	if id == "" {
+		return
+	}
+	handleStoreMu.Lock()
+	defer handleStoreMu.Unlock()
+	handleStore[id] = handleEntry{
+		Info:     info,
+		Selector: selector,
+		TabID:    atomic.LoadInt64(&activeTab),
+		Seen:     time.Now(),
+	}
+}
+
+// registerDiscoveredHandles promotes every element in a dom.discoverElements
+// response into the registry. It's called from printResponse rather than
+// from a Renderer, so discover's handles show up in .handle/tab-completion
+// no matter which Renderer (tty, json, ndjson) is formatting the output.
+func registerDiscoveredHandles(result json.RawMessage) {
+	var disc struct {
+		Elements []struct {
+			Tag      string `json:"tag"`
+			Text     string `json:"text"`
+			HandleId string `json:"handleId"`
+			Selector string `json:"selector"`
+		} `json:"elements"`
+	}
+	if json.Unmarshal(result, &disc) != nil {
+		return
+	}
+	for _, el := range disc.Elements {
+		registerHandle(el.HandleId, el.Selector, elInfo{Tag: el.Tag, Text: el.Text})
+	}
+}
+
+// invalidateHandles drops the registry when the page it describes goes away.
+func invalidateHandles() {
+	handleStoreMu.Lock()
+	handleStore = map[string]handleEntry{}
+	handleStoreMu.Unlock()
+}
+
+func sortedHandleIDs() []string {
+	handleStoreMu.Lock()
+	defer handleStoreMu.Unlock()
+	ids := make([]string, 0, len(handleStore))
+	for id := range handleStore {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	return ids
+}
+
+// handleIDCompletions feeds readline.PcItemDynamic for `click`/`box` args.
+func handleIDCompletions(string) []string {
+	return sortedHandleIDs()
+}
+
+func listHandles() {
+	ids := sortedHandleIDs()
+	if len(ids) == 0 {
+		out("%s(no known handles — try q <selector> or discover)%s", cDim, cReset)
+		return
+	}
+	out("%s%d known handle(s)%s", cBold, len(ids), cReset)
+	for _, id := range ids {
+		handleStoreMu.Lock()
+		e := handleStore[id]
+		handleStoreMu.Unlock()
+		out("  %s%s%s  %s  %s(%s)%s", cGreen, id, cReset, formatEl(e.Info), cDim, e.Selector, cReset)
+	}
+}
+
+func printHandle(id string) {
+	handleStoreMu.Lock()
+	e, ok := handleStore[id]
+	handleStoreMu.Unlock()
+	if !ok {
+		out("%sunknown handle:%s %s", cRed, cReset, id)
+		return
+	}
+	out("%s%s%s  %s", cGreen, id, cReset, formatEl(e.Info))
+	out("  %sselector:%s %s  %stab:%s %d  %sseen:%s %s",
+		cDim, cReset, e.Selector, cDim, cReset, e.TabID, cDim, cReset, e.Seen.Format("15:04:05"))
+}
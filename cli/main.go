@@ -15,7 +15,6 @@ import (
 	"time"
 
 	"github.com/chzyer/readline"
-	"github.com/gorilla/websocket"
 )
 
 const (
@@ -28,22 +27,36 @@ const (
 )
 
 var (
-	conn         *websocket.Conn
-	connMu       sync.Mutex
-	activeTab    int64
-	activeAlias  int  = -1 // short alias currently selected
-	oneshot      bool      // -c mode: suppress disconnect noise
-	counter      uint64
-	pending      sync.Map // id -> *pendingReq
-	showEvents   int32 = 1
-	rl           *readline.Instance
-	disconnected = make(chan struct{})
+	conn        Transport
+	connMu      sync.Mutex
+	activeTab   int64
+	activeAlias int  = -1 // short alias currently selected
+	oneshot     bool      // -c mode / --batch: suppress disconnect noise, no reconnect
+	noReconnect bool      // --no-reconnect
+	dialAddr    string    // kept so reconnect can redial the same URL
+	counter     uint64
+	pending     sync.Map // id -> *pendingReq
+	showEvents  int32 = 1
+	rl          *readline.Instance
 
 	// Tab alias map: index 0-9 -> real Chrome tab ID
 	tabMap   []tabEntry
 	tabMapMu sync.Mutex
+
+	// lastCmdErr holds the error (if any) from the most recently dispatched
+	// command, for callers like playScript that need error-abort semantics.
+	// It is set by sendCommand, sendRawJSON and printResponse; shorthands that
+	// go through sendAndWait (q, cookies load/save) print their errors
+	// without setting it.
+	lastCmdErr error
 )
 
+// dispatchChecked runs dispatch and reports whether the command errored.
+func dispatchChecked(line string) error {
+	dispatch(line)
+	return lastCmdErr
+}
+
 type tabEntry struct {
 	ID     int    `json:"id"`
 	URL    string `json:"url"`
@@ -53,6 +66,7 @@ type tabEntry struct {
 
 type pendingReq struct {
 	action string
+	msg    map[string]interface{} // original outgoing message, kept for reconnect replay
 	ch     chan []byte
 }
 
@@ -75,17 +89,24 @@ var protocolActions = []string{
 
 func buildCompleter() readline.AutoCompleter {
 	var items []readline.PrefixCompleterInterface
-	for _, c := range []string{".help", ".quit", ".exit", ".tab", ".tabs", ".events", ".status"} {
+	for _, c := range []string{".help", ".quit", ".exit", ".tab", ".tabs", ".events", ".status",
+		".reconnect", ".record", ".stop", ".play"} {
 		items = append(items, readline.PcItem(c))
 	}
+	items = append(items,
+		readline.PcItem(".handles"),
+		readline.PcItem(".handle", readline.PcItemDynamic(handleIDCompletions)))
 	for _, a := range protocolActions {
 		items = append(items, readline.PcItem(a))
 	}
-	// Shorthands
-	for _, s := range []string{"go", "click", "type", "sd", "su", "q",
+	// Shorthands. click/box additionally complete known el_N handles.
+	items = append(items,
+		readline.PcItem("click", readline.PcItemDynamic(handleIDCompletions)),
+		readline.PcItem("box", readline.PcItemDynamic(handleIDCompletions)))
+	for _, s := range []string{"go", "type", "sd", "su", "q",
 		"wait", "eval", "js", "title", "url", "html", "ss", "screenshot",
 		"reload", "back", "forward", "clear", "focus", "key", "discover",
-		"cookies", "box"} {
+		"cookies", "sub", "unsub", "subs"} {
 		items = append(items, readline.PcItem(s))
 	}
 	return readline.NewPrefixCompleter(items...)
@@ -94,12 +115,7 @@ func buildCompleter() readline.AutoCompleter {
 // --- output helpers ---
 
 func out(format string, args ...interface{}) {
-	msg := fmt.Sprintf(format, args...)
-	if rl != nil {
-		fmt.Fprintln(rl.Stdout(), msg)
-	} else {
-		fmt.Println(msg)
-	}
+	activeRenderer.Info(format, args...)
 }
 
 func nextID() string {
@@ -140,31 +156,29 @@ func updateTabMap(tabs []tabEntry) {
 }
 
 func printPrompt() {
-	if rl == nil {
-		return
-	}
 	tabMapMu.Lock()
 	alias := activeAlias
 	tabMapMu.Unlock()
-
-	if alias >= 0 {
-		rl.SetPrompt(fmt.Sprintf("hb[%d]> ", alias))
-	} else {
-		rl.SetPrompt("hb> ")
-	}
+	activeRenderer.Prompt(alias)
 }
 
 // --- read loop ---
 
 func readLoop() {
-	defer close(disconnected)
 	for {
-		_, msg, err := conn.ReadMessage()
+		msg, err := getConn().ReadMessage()
 		if err != nil {
-			if !oneshot {
-				out("\n%s[disconnected]%s %v", cRed, cReset, err)
+			if oneshot || noReconnect {
+				if !oneshot {
+					out("\n%s[disconnected]%s %v", cRed, cReset, err)
+				}
+				markDisconnected()
+				return
 			}
-			return
+			out("\n%s[disconnected]%s %v %s(reconnecting...)%s", cRed, cReset, err, cDim, cReset)
+			failUnsafePending()
+			reconnectLoop()
+			continue
 		}
 
 		var envelope map[string]json.RawMessage
@@ -192,6 +206,19 @@ func readLoop() {
 			}
 		}
 
+		// Stale handles: a navigation or reload tears down the old DOM, so
+		// any el_N registered against it no longer resolves to anything.
+		// Script wait-for waiters are woken here too, whether or not events
+		// are being displayed.
+		if evtName, ok := envelope["event"]; ok {
+			var name string
+			json.Unmarshal(evtName, &name)
+			if strings.Contains(name, "navigat") || strings.Contains(name, "reload") {
+				invalidateHandles()
+			}
+			notifyEventWaiters(name)
+		}
+
 		// Print event
 		if atomic.LoadInt32(&showEvents) == 1 {
 			printEvent(msg)
@@ -209,8 +236,8 @@ func printEvent(msg []byte) {
 	if json.Unmarshal(msg, &evt) != nil || evt.Event == "" {
 		return
 	}
-	pretty, _ := json.MarshalIndent(json.RawMessage(evt.Data), "  ", "  ")
-	out("%s[%s]%s %s", cYellow, evt.Event, cReset, string(pretty))
+	dispatchSubscriptions(evt.Event, evt.Data)
+	activeRenderer.Event(evt.Event, evt.Data)
 }
 
 func printResponse(raw []byte, action string) {
@@ -225,140 +252,36 @@ func printResponse(raw []byte, action string) {
 	}
 
 	if resp.Error != "" {
-		out("%serror:%s %s", cRed, cReset, resp.Error)
-		return
-	}
-
-	// Screenshot: save to file instead of dumping base64
-	if action == "tabs.screenshot" {
-		var obj map[string]interface{}
-		if json.Unmarshal(resp.Result, &obj) == nil {
-			if dataUrl, ok := obj["dataUrl"].(string); ok {
-				saveScreenshot(dataUrl)
-				return
-			}
-		}
-	}
-
-	// tabs.list: formatted table with short aliases
-	if action == "tabs.list" {
-		var tabs []tabEntry
-		if json.Unmarshal(resp.Result, &tabs) == nil && len(tabs) > 0 {
-			updateTabMap(tabs)
-			for i, t := range tabs {
-				marker := "  "
-				if t.Active {
-					marker = cGreen + "* " + cReset
-				}
-				title := t.Title
-				if len(title) > 50 {
-					title = title[:47] + "..."
-				}
-				selected := " "
-				if int64(t.ID) == atomic.LoadInt64(&activeTab) {
-					selected = cGreen + ">" + cReset
-				}
-				out("%s %s%d%s  %s%d%s  %s  %s%s%s",
-					selected, cBold, i, cReset,
-					cDim, t.ID, cReset,
-					t.URL,
-					cDim, title, cReset)
-				_ = marker
-			}
-			out("%s  .tab <0-%d> to target a tab%s", cDim, len(tabs)-1, cReset)
-			return
+		lastCmdErr = fmt.Errorf("%s", resp.Error)
+	} else if len(resp.Result) > 0 {
+		lastResults.Store(action, resp.Result)
+		if action == "dom.discoverElements" {
+			registerDiscoveredHandles(resp.Result)
 		}
 	}
 
-	// dom.discoverElements: formatted element list
-	if action == "dom.discoverElements" {
-		var disc struct {
-			Elements []struct {
-				Type      string `json:"type"`
-				Tag       string `json:"tag"`
-				Text      string `json:"text"`
-				Href      string `json:"href"`
-				HandleId  string `json:"handleId"`
-				Selector  string `json:"selector"`
-				InputType string `json:"inputType"`
-				Name      string `json:"name"`
-				Placeholder string `json:"placeholder"`
-			} `json:"elements"`
-		}
-		if json.Unmarshal(resp.Result, &disc) == nil && len(disc.Elements) > 0 {
-			links, buttons, inputs := 0, 0, 0
-			for _, el := range disc.Elements {
-				switch el.Type {
-				case "link":
-					links++
-				case "button":
-					buttons++
-				case "input":
-					inputs++
-				}
-			}
-			out("%s%d elements%s  %s(%d links, %d buttons, %d inputs)%s",
-				cBold, len(disc.Elements), cReset, cDim, links, buttons, inputs, cReset)
-			out("")
-			for _, el := range disc.Elements {
-				label := el.Text
-				if len(label) > 50 {
-					label = label[:47] + "..."
-				}
-				switch el.Type {
-				case "link":
-					href := el.Href
-					if len(href) > 60 {
-						href = href[:57] + "..."
-					}
-					out("  %s%s%s  %s[link]%s  %s\"%s\"%s  %s→ %s%s",
-						cGreen, el.HandleId, cReset, cYellow, cReset,
-						cDim, label, cReset, cDim, href, cReset)
-				case "button":
-					out("  %s%s%s  %s[btn]%s   %s\"%s\"%s  %s%s%s",
-						cGreen, el.HandleId, cReset, cYellow, cReset,
-						cDim, label, cReset, cDim, el.Selector, cReset)
-				case "input":
-					desc := el.InputType
-					if el.Name != "" {
-						desc += " name=" + el.Name
-					}
-					if el.Placeholder != "" {
-						desc += " \"" + el.Placeholder + "\""
-					}
-					out("  %s%s%s  %s[input]%s %s%s%s  %s%s%s",
-						cGreen, el.HandleId, cReset, cYellow, cReset,
-						cDim, desc, cReset, cDim, el.Selector, cReset)
-				}
-			}
-			return
-		}
-	}
+	activeRenderer.Response(seqFromID(resp.ID), action, resp.Result, resp.Error)
+}
 
-	// Default: pretty-print JSON
-	var v interface{}
-	json.Unmarshal(resp.Result, &v)
-	pretty, _ := json.MarshalIndent(v, "", "  ")
-	out("%s", string(pretty))
+func seqFromID(id string) uint64 {
+	n, _ := strconv.ParseUint(strings.TrimPrefix(id, "hb_"), 10, 64)
+	return n
 }
 
-func saveScreenshot(dataUrl string) {
+func saveScreenshot(dataUrl string) (string, int, error) {
 	idx := strings.Index(dataUrl, ",")
 	if idx < 0 {
-		out("%serror:%s invalid screenshot data", cRed, cReset)
-		return
+		return "", 0, fmt.Errorf("invalid screenshot data")
 	}
 	data, err := base64.StdEncoding.DecodeString(dataUrl[idx+1:])
 	if err != nil {
-		out("%serror:%s decode: %v", cRed, cReset, err)
-		return
+		return "", 0, fmt.Errorf("decode: %w", err)
 	}
 	name := fmt.Sprintf("screenshot_%s.png", time.Now().Format("20060102_150405"))
 	if err := os.WriteFile(name, data, 0644); err != nil {
-		out("%serror:%s write: %v", cRed, cReset, err)
-		return
+		return "", 0, fmt.Errorf("write: %w", err)
 	}
-	out("%sscreenshot:%s %s (%d bytes)", cGreen, cReset, name, len(data))
+	return name, len(data), nil
 }
 
 // --- low-level send helper (returns result, for chaining) ---
@@ -375,7 +298,7 @@ func sendAndWait(action string, params map[string]interface{}) (json.RawMessage,
 		msg["tabId"] = tab
 	}
 
-	req := &pendingReq{action: action, ch: make(chan []byte, 1)}
+	req := &pendingReq{action: action, msg: msg, ch: make(chan []byte, 1)}
 	pending.Store(id, req)
 
 	if err := wsSend(msg); err != nil {
@@ -383,6 +306,9 @@ func sendAndWait(action string, params map[string]interface{}) (json.RawMessage,
 		return nil, err
 	}
 
+	ctx, cancel := commandContext()
+	defer cancel()
+
 	select {
 	case raw := <-req.ch:
 		var resp struct {
@@ -394,10 +320,10 @@ func sendAndWait(action string, params map[string]interface{}) (json.RawMessage,
 			return nil, fmt.Errorf("%s", resp.Error)
 		}
 		return resp.Result, nil
-	case <-time.After(35 * time.Second):
+	case <-ctx.Done():
 		pending.Delete(id)
-		return nil, fmt.Errorf("timeout")
-	case <-disconnected:
+		return nil, fmt.Errorf("%w", ctx.Err())
+	case <-currentDisconnected():
 		pending.Delete(id)
 		return nil, fmt.Errorf("disconnected")
 	}
@@ -446,40 +372,6 @@ func formatEl(el elInfo) string {
 	return desc
 }
 
-// loadCookies: read JSON file and inject each cookie via cookies.set
-func loadCookies(args string) {
-	parts := strings.Fields(args)
-	file := "cookies.json"
-	if len(parts) > 1 {
-		file = parts[1]
-	}
-
-	data, err := os.ReadFile(file)
-	if err != nil {
-		out("%serror:%s %v", cRed, cReset, err)
-		return
-	}
-
-	var cookies []map[string]interface{}
-	if err := json.Unmarshal(data, &cookies); err != nil {
-		out("%serror:%s invalid JSON: %v", cRed, cReset, err)
-		return
-	}
-
-	ok, fail := 0, 0
-	for _, c := range cookies {
-		params, _ := json.Marshal(map[string]interface{}{"cookie": c})
-		_, err := sendAndWait("cookies.set", map[string]interface{}{"cookie": c})
-		_ = params
-		if err != nil {
-			fail++
-		} else {
-			ok++
-		}
-	}
-	out("%s%d cookies loaded%s, %d failed", cGreen, ok, cReset, fail)
-}
-
 // doQuery: find ALL matching elements, register handles, show info — single round-trip
 func doQuery(selector string) {
 	out("%s-> q %s%s", cDim, selector, cReset)
@@ -501,6 +393,7 @@ func doQuery(selector string) {
 
 	out("%s%d match(es)%s", cBold, len(elements), cReset)
 	for _, el := range elements {
+		registerHandle(el.HandleId, selector, el.elInfo)
 		out("  %s%s%s  %s", cGreen, el.HandleId, cReset, formatEl(el.elInfo))
 	}
 }
@@ -508,10 +401,15 @@ func doQuery(selector string) {
 // --- command dispatch ---
 
 func dispatch(line string) {
+	lastCmdErr = nil
+
 	if strings.HasPrefix(line, ".") {
 		dotCommand(line)
 		return
 	}
+
+	recordLine(line)
+
 	if strings.HasPrefix(line, "{") {
 		sendRawJSON(line)
 		return
@@ -721,8 +619,10 @@ func tryShorthand(line string) bool {
 			sendCommand("cookies.getAll", "{}")
 		} else if strings.HasPrefix(rest, "load") {
 			loadCookies(rest)
+		} else if strings.HasPrefix(rest, "save") {
+			saveCookies(rest)
 		} else {
-			out("%susage: cookies [get|load <file>]%s", cDim, cReset)
+			out("%susage: cookies [get|load <file>|save <file> [format]]%s", cDim, cReset)
 		}
 		return true
 
@@ -738,6 +638,27 @@ func tryShorthand(line string) bool {
 			sendCommand("dom.boundingBox", fmt.Sprintf(`{"selector":%q}`, rest))
 		}
 		return true
+
+	case "sub":
+		// sub tab.navigated  OR  sub network.request statusCode >= 400
+		if rest == "" {
+			out("%susage: sub <topic> [filter-expr]%s", cDim, cReset)
+			return true
+		}
+		subscribeCmd(rest)
+		return true
+
+	case "unsub":
+		if rest == "" {
+			out("%susage: unsub <id>%s", cDim, cReset)
+			return true
+		}
+		unsubscribeCmd(rest)
+		return true
+
+	case "subs":
+		listSubscriptions()
+		return true
 	}
 
 	return false
@@ -746,6 +667,7 @@ func tryShorthand(line string) bool {
 func sendCommand(action, paramsJSON string) {
 	var params json.RawMessage
 	if err := json.Unmarshal([]byte(paramsJSON), &params); err != nil {
+		lastCmdErr = fmt.Errorf("invalid params: %w", err)
 		out("%sinvalid params:%s %v", cRed, cReset, err)
 		out("  %susage: %s {\"key\": \"value\"}%s", cDim, action, cReset)
 		return
@@ -761,25 +683,31 @@ func sendCommand(action, paramsJSON string) {
 		msg["tabId"] = tab
 	}
 
-	req := &pendingReq{action: action, ch: make(chan []byte, 1)}
+	req := &pendingReq{action: action, msg: msg, ch: make(chan []byte, 1)}
 	pending.Store(id, req)
 
 	if err := wsSend(msg); err != nil {
 		pending.Delete(id)
+		lastCmdErr = fmt.Errorf("send failed: %w", err)
 		out("%ssend failed:%s %v", cRed, cReset, err)
 		return
 	}
 
 	out("%s-> %s%s", cDim, action, cReset)
 
+	ctx, cancel := commandContext()
+	defer cancel()
+
 	select {
 	case resp := <-req.ch:
 		printResponse(resp, action)
-	case <-time.After(35 * time.Second):
+	case <-ctx.Done():
 		pending.Delete(id)
-		out("%stimeout%s (35s)", cRed, cReset)
-	case <-disconnected:
+		lastCmdErr = fmt.Errorf("timeout (%s)", cmdTimeout)
+		out("%stimeout%s (%s)", cRed, cReset, cmdTimeout)
+	case <-currentDisconnected():
 		pending.Delete(id)
+		lastCmdErr = fmt.Errorf("disconnected")
 		out("%sdisconnected%s", cRed, cReset)
 	}
 }
@@ -787,6 +715,7 @@ func sendCommand(action, paramsJSON string) {
 func sendRawJSON(raw string) {
 	var msg map[string]interface{}
 	if err := json.Unmarshal([]byte(raw), &msg); err != nil {
+		lastCmdErr = fmt.Errorf("invalid JSON: %w", err)
 		out("%sinvalid JSON:%s %v", cRed, cReset, err)
 		return
 	}
@@ -798,25 +727,31 @@ func sendRawJSON(raw string) {
 	}
 
 	action, _ := msg["action"].(string)
-	req := &pendingReq{action: action, ch: make(chan []byte, 1)}
+	req := &pendingReq{action: action, msg: msg, ch: make(chan []byte, 1)}
 	pending.Store(id, req)
 
 	if err := wsSend(msg); err != nil {
 		pending.Delete(id)
+		lastCmdErr = fmt.Errorf("send failed: %w", err)
 		out("%ssend failed:%s %v", cRed, cReset, err)
 		return
 	}
 
 	out("%s-> %s%s", cDim, action, cReset)
 
+	ctx, cancel := commandContext()
+	defer cancel()
+
 	select {
 	case resp := <-req.ch:
 		printResponse(resp, action)
-	case <-time.After(35 * time.Second):
+	case <-ctx.Done():
 		pending.Delete(id)
-		out("%stimeout%s (35s)", cRed, cReset)
-	case <-disconnected:
+		lastCmdErr = fmt.Errorf("timeout (%s)", cmdTimeout)
+		out("%stimeout%s (%s)", cRed, cReset, cmdTimeout)
+	case <-currentDisconnected():
 		pending.Delete(id)
+		lastCmdErr = fmt.Errorf("disconnected")
 		out("%sdisconnected%s", cRed, cReset)
 	}
 }
@@ -840,6 +775,8 @@ func dotCommand(line string) {
 		out("  q <sel>              find all matches (handles + info)")
 		out("  wait <sel>           wait for selector")
 		out("  discover             list interactive elements")
+		out("  .handles             list known el_N handles")
+		out("  .handle <id>         re-print info for a handle")
 		out("")
 		out("%sInteract%s", cBold, cReset)
 		out("  click <sel|handle>   human click")
@@ -854,14 +791,39 @@ func dotCommand(line string) {
 		out("  ss                   screenshot (saves to file)")
 		out("  box <sel>            bounding box")
 		out("  cookies              dump all cookies")
+		out("  cookies load <file>  import cookies (json, netscape, extension, devtools)")
+		out("  cookies save <file> [format]  export cookies")
 		out("")
 		out("%sMeta%s", cBold, cReset)
 		out("  .tabs                list tabs (0-9 aliases)")
 		out("  .tab <n>             set active tab by alias or ID")
 		out("  .events              toggle event display")
 		out("  .status              connection info")
+		out("  .reconnect           force a reconnect")
 		out("  .quit                exit")
 		out("")
+		out("%sAutomation%s", cBold, cReset)
+		out("  .record <file>       capture dispatched commands to a playbook")
+		out("  .stop                stop the current recording")
+		out("  .play <file> [--loop N] [--delay Xms] [--continue-on-error]")
+		out("                       replay a playbook ($" + "{env:NAME}, $" + "{result:<action>.<path>})")
+		out("")
+		out("%sSubscriptions%s", cBold, cReset)
+		out("  sub <topic> [filter]  e.g. sub network.request statusCode >= 400")
+		out("  unsub <id>            remove a subscription")
+		out("  subs                  list active subscriptions (persisted in ~/.hb_subs.json)")
+		out("")
+		out("%sNon-interactive%s", cBold, cReset)
+		out("  hb -f script.hb      run a script file and exit")
+		out("  ... | hb             piped stdin runs as a script automatically")
+		out("  hb --json            ndjson output, one reply object per line")
+		out("  hb --compress=auto|on|off --compress-level=N  permessage-deflate tuning")
+		out("  hb --watch 'tab.navigated'  wait for one matching event and exit")
+		out("  sleep 500ms | wait-for <event> | assert <path> [== <value>]  (script directives)")
+		out("  hb --record session.jsonl   log every frame to a session file")
+		out("  hb --replay session.jsonl   replay against -addr, or mock it if -addr is unset")
+		out("  hb diff a.jsonl b.jsonl      diff two sessions' recorded responses")
+		out("")
 		out("%sRaw mode%s", cBold, cReset)
 		out("  action.name {json}   full protocol command")
 		out("  {raw json}           raw WebSocket message")
@@ -869,7 +831,7 @@ func dotCommand(line string) {
 
 	case ".quit", ".exit":
 		out(cDim + "bye" + cReset)
-		conn.Close()
+		getConn().Close()
 		os.Exit(0)
 
 	case ".tab":
@@ -932,6 +894,29 @@ func dotCommand(line string) {
 	case ".tabs":
 		sendCommand("tabs.list", "{}")
 
+	case ".reconnect":
+		out("%sforcing reconnect...%s", cDim, cReset)
+		getConn().Close()
+
+	case ".handles":
+		listHandles()
+
+	case ".handle":
+		if len(parts) < 2 {
+			out("%susage: .handle <id>%s", cDim, cReset)
+			return
+		}
+		printHandle(parts[1])
+
+	case ".record":
+		startRecording(line)
+
+	case ".stop":
+		stopRecording()
+
+	case ".play":
+		playScript(line)
+
 	case ".events":
 		if atomic.LoadInt32(&showEvents) == 1 {
 			atomic.StoreInt32(&showEvents, 0)
@@ -954,6 +939,14 @@ func dotCommand(line string) {
 			out("tab:       %d", tab)
 		}
 		out("events:    %s", ev)
+		switch {
+		case compressionNegotiated() && compressionNoContextTakeover():
+			out("compress:  %son%s %s(no_context_takeover)%s", cGreen, cReset, cDim, cReset)
+		case compressionNegotiated():
+			out("compress:  %son%s", cGreen, cReset)
+		default:
+			out("compress:  %soff%s", cDim, cReset)
+		}
 
 	default:
 		out("%sunknown: %s%s %s(try .help)%s", cRed, cmd, cReset, cDim, cReset)
@@ -963,37 +956,152 @@ func dotCommand(line string) {
 // --- main ---
 
 func main() {
-	addr := flag.String("addr", "ws://localhost:7331", "WebSocket address")
+	if len(os.Args) > 1 && os.Args[1] == "diff" {
+		runDiffCmd(os.Args[2:])
+		return
+	}
+
+	addr := flag.String("addr", "ws://localhost:7331", "Server address: ws://, wss://, h2://, or unix://")
 	cmd := flag.String("c", "", "Execute command and exit")
+	scriptFile := flag.String("f", "", "Run newline-delimited commands from a script file and exit")
+	format := flag.String("format", "text", "Output format: text|json|ndjson")
+	jsonOut := flag.Bool("json", false, "Shorthand for --format ndjson")
+	batch := flag.Bool("batch", false, "Read newline-delimited commands from stdin")
+	continueOnError := flag.Bool("continue-on-error", false, "In --batch/-f/piped-stdin mode, keep going after a failing command")
+	flag.BoolVar(&noReconnect, "no-reconnect", false, "Exit on disconnect instead of reconnecting")
+	flag.DurationVar(&cmdTimeout, "timeout", 35*time.Second, "Per-command deadline before giving up")
+	flag.StringVar(&tlsCAFile, "cafile", "", "PEM file of CA certificates to trust for wss:// and h2://")
+	flag.BoolVar(&tlsInsecure, "insecure", false, "Skip TLS certificate verification for wss:// and h2://")
+	flag.StringVar(&tlsClientCert, "client-cert", "", "PEM file with a client certificate and key for mTLS")
+	flag.StringVar(&compressMode, "compress", compressMode, "permessage-deflate negotiation: auto|on|off")
+	flag.IntVar(&compressLevel, "compress-level", compressLevel, "flate compression level (0 = library default)")
+	watch := flag.String("watch", "", "Oneshot: wait for one event matching 'topic [filter-expr]' and exit")
+	recordFile := flag.String("record", "", "Log every frame (direction, timestamp, payload) to a session file")
+	replayFile := flag.String("replay", "", "Replay a recorded session: against -addr if given explicitly, else an in-process mock")
 	flag.Parse()
-
-	c, _, err := websocket.DefaultDialer.Dial(*addr, nil)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "%sfailed to connect:%s %v\n", cRed, cReset, err)
-		fmt.Fprintf(os.Stderr, "%sis the server running? (node index.js)%s\n", cDim, cReset)
+	switch compressMode {
+	case "auto", "on", "off":
+	default:
+		fmt.Fprintf(os.Stderr, "unknown --compress %q (want auto|on|off)\n", compressMode)
+		os.Exit(1)
+	}
+	addrSet := false
+	flag.Visit(func(f *flag.Flag) {
+		if f.Name == "addr" {
+			addrSet = true
+		}
+	})
+	dialAddr = *addr
+	pipedStdin := *cmd == "" && *scriptFile == "" && !*batch && *watch == "" && stdinIsPipe()
+	oneshot = *cmd != "" || *batch || *scriptFile != "" || pipedStdin || *watch != ""
+
+	initSubscriptions()
+
+	switch {
+	case *jsonOut:
+		activeRenderer = jsonRenderer{compact: true}
+	case *format == "json":
+		activeRenderer = jsonRenderer{compact: false}
+	case *format == "ndjson":
+		activeRenderer = jsonRenderer{compact: true}
+	case *format == "text":
+		activeRenderer = ttyRenderer{}
+	default:
+		fmt.Fprintf(os.Stderr, "unknown --format %q (want text|json|ndjson)\n", *format)
 		os.Exit(1)
 	}
-	conn = c
+
+	var replayFrames []sessionFrame
+	var c Transport
+	var err error
+
+	if *replayFile != "" {
+		replayFrames, err = loadSessionLog(*replayFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	switch {
+	case *replayFile != "" && !addrSet:
+		// No live server named: answer from the recording in-process.
+		c = newMockTransport(replayFrames)
+		noReconnect = true
+	default:
+		c, err = dialTransport(*addr)
+		if err != nil {
+			if oneshot || noReconnect {
+				fmt.Fprintf(os.Stderr, "%sfailed to connect:%s %v\n", cRed, cReset, err)
+				fmt.Fprintf(os.Stderr, "%sis the server running? (node index.js)%s\n", cDim, cReset)
+				os.Exit(1)
+			}
+			fmt.Fprintf(os.Stderr, "%sfailed to connect:%s %v %s(retrying...)%s\n", cRed, cReset, err, cDim, cReset)
+			c = dialBackoff()
+		}
+	}
+
+	if *recordFile != "" {
+		rc, err := newRecordingTransport(c, *recordFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "--record %s: %v\n", *recordFile, err)
+			os.Exit(1)
+		}
+		c = rc
+	}
+	setConn(c)
 
 	sig := make(chan os.Signal, 1)
 	signal.Notify(sig, os.Interrupt)
 	go func() {
 		<-sig
 		fmt.Println()
-		conn.Close()
+		getConn().Close()
 		os.Exit(0)
 	}()
 
 	go readLoop()
 
+	if *replayFile != "" && addrSet {
+		n := replaySendsAgainstLive(replayFrames)
+		out("%sreplayed %d recorded command(s) against %s%s", cGreen, n, *addr, cReset)
+	}
+
+	// --watch: block for one matching event, print it, exit.
+	if *watch != "" {
+		runWatch(*watch)
+	}
+
 	// Non-interactive mode: execute command and exit
 	if *cmd != "" {
-		oneshot = true
 		dispatch(*cmd)
-		conn.Close()
+		getConn().Close()
 		os.Exit(0)
 	}
 
+	// Script file: run it and exit, same semantics as --batch.
+	if *scriptFile != "" {
+		f, err := os.Open(*scriptFile)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "%s\n", err)
+			getConn().Close()
+			os.Exit(1)
+		}
+		exitCode := runBatch(f, *continueOnError)
+		f.Close()
+		getConn().Close()
+		os.Exit(exitCode)
+	}
+
+	// Batch mode and piped stdin (`echo "tabs.list" | hb`) both drain
+	// newline-delimited commands from stdin, exiting with the first
+	// failure's status unless told to keep going.
+	if *batch || pipedStdin {
+		exitCode := runBatch(os.Stdin, *continueOnError)
+		getConn().Close()
+		os.Exit(exitCode)
+	}
+
 	home, _ := os.UserHomeDir()
 	histFile := filepath.Join(home, ".hb_history")
 
@@ -1026,5 +1134,5 @@ func main() {
 		dispatch(line)
 	}
 
-	conn.Close()
+	getConn().Close()
 }
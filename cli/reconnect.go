@@ -0,0 +1,168 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math/rand"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Auto-reconnect: when readLoop hits a read error, we redial dialAddr with
+// jittered exponential backoff, then replay whichever in-flight requests are
+// safe to replay (read-only actions) and fail the rest with a clear error
+// instead of leaving their callers hanging until the command deadline.
+
+var (
+	disconnectedMu sync.Mutex
+	disconnected   = make(chan struct{})
+
+	// cmdTimeout bounds how long a single dispatched command waits for its
+	// response before giving up (--timeout). Every outgoing request carries
+	// a context built from this, so a hung server can't freeze the prompt.
+	cmdTimeout = 35 * time.Second
+)
+
+func commandContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), cmdTimeout)
+}
+
+func getConn() Transport {
+	connMu.Lock()
+	defer connMu.Unlock()
+	return conn
+}
+
+func setConn(c Transport) {
+	connMu.Lock()
+	conn = c
+	connMu.Unlock()
+}
+
+// currentDisconnected returns the channel that's closed when the active
+// connection drops. It's re-created on every successful reconnect, so
+// callers must re-evaluate it on each select rather than caching it.
+func currentDisconnected() chan struct{} {
+	disconnectedMu.Lock()
+	defer disconnectedMu.Unlock()
+	return disconnected
+}
+
+func markDisconnected() {
+	disconnectedMu.Lock()
+	select {
+	case <-disconnected:
+		// already closed
+	default:
+		close(disconnected)
+	}
+	disconnectedMu.Unlock()
+}
+
+func resetDisconnected() {
+	disconnectedMu.Lock()
+	disconnected = make(chan struct{})
+	disconnectedMu.Unlock()
+}
+
+// safeToReplay reports whether re-issuing a request after reconnect is free
+// of side effects. Anything that mutates page/browser state (clicks, typing,
+// navigation, ...) must not be silently replayed.
+func safeToReplay(action string) bool {
+	switch {
+	case action == "tabs.list",
+		action == "cookies.getAll",
+		action == "dom.discoverElements",
+		action == "dom.queryAllInfo":
+		return true
+	case strings.HasPrefix(action, "dom.querySelector"),
+		strings.HasPrefix(action, "dom.get"),
+		action == "dom.boundingBox":
+		return true
+	default:
+		return false
+	}
+}
+
+// failUnsafePending immediately fails every in-flight request that isn't
+// safe to silently replay (anything with side effects), rather than leaving
+// its caller to find out at the command timeout. It's called right when the
+// read error is seen, before dialBackoff/replayPending even start, so the
+// caller gets the real reason ("not safe to auto-replay") instead of racing
+// a generic disconnect notification against the reconnect attempt.
+//
+// Requests that ARE safe to replay are left in pending: they keep waiting on
+// their own command context, and replayPending resends them once the new
+// connection is up, so a reconnect that completes well within cmdTimeout is
+// invisible to the caller.
+func failUnsafePending() {
+	pending.Range(func(key, value interface{}) bool {
+		id := key.(string)
+		req := value.(*pendingReq)
+		if safeToReplay(req.action) {
+			return true
+		}
+		pending.Delete(id)
+		errMsg, _ := json.Marshal(map[string]string{
+			"id":    id,
+			"error": fmt.Sprintf("connection lost; %s is not safe to auto-replay", req.action),
+		})
+		select {
+		case req.ch <- errMsg:
+		default:
+		}
+		return true
+	})
+}
+
+// replayPending re-sends every still-pending request (failUnsafePending has
+// already pruned anything unsafe to replay) over the new connection.
+func replayPending() {
+	pending.Range(func(key, value interface{}) bool {
+		wsSend(value.(*pendingReq).msg)
+		return true
+	})
+}
+
+func jitter(d time.Duration) time.Duration {
+	if d <= 0 {
+		return d
+	}
+	return d/2 + time.Duration(rand.Int63n(int64(d)/2+1))
+}
+
+// dialBackoff redials dialAddr (via whichever Transport its scheme selects)
+// with 100ms->30s jittered exponential backoff until it succeeds. Used both
+// for the initial connection and for reconnects, so a server that isn't up
+// yet no longer costs a hard exit.
+func dialBackoff() Transport {
+	const maxBackoff = 30 * time.Second
+	backoff := 100 * time.Millisecond
+
+	for {
+		c, err := dialTransport(dialAddr)
+		if err == nil {
+			return c
+		}
+		time.Sleep(jitter(backoff))
+		backoff *= 2
+		if backoff > maxBackoff {
+			backoff = maxBackoff
+		}
+	}
+}
+
+// reconnectLoop redials, replays in-flight requests, and refreshes the tab
+// list against the new connection, surfacing a clear "reconnected" notice.
+// The tab refresh runs on its own goroutine: reconnectLoop is called from
+// readLoop, which has to be free to read that response.
+func reconnectLoop() {
+	c := dialBackoff()
+	setConn(c)
+	resetDisconnected()
+	replayPending()
+	out("%sreconnected%s to %s", cGreen, cReset, dialAddr)
+	go sendCommand("tabs.list", "{}")
+}
@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"sync/atomic"
+)
+
+// Renderer is the single side-effect boundary for everything the client
+// prints: command responses, server-pushed events, informational text, and
+// the interactive prompt. Swapping the renderer is what lets --format
+// switch between the colored TTY experience and machine-readable output.
+type Renderer interface {
+	Response(seq uint64, action string, result json.RawMessage, errStr string)
+	Event(name string, data json.RawMessage)
+	Info(format string, args ...interface{})
+	Prompt(alias int)
+}
+
+var activeRenderer Renderer = &ttyRenderer{}
+
+// --- TTY renderer: today's colored, action-aware formatting ---
+
+type ttyRenderer struct{}
+
+func (ttyRenderer) Info(format string, args ...interface{}) {
+	msg := fmt.Sprintf(format, args...)
+	if rl != nil {
+		fmt.Fprintln(rl.Stdout(), msg)
+	} else {
+		fmt.Println(msg)
+	}
+}
+
+func (r ttyRenderer) Prompt(alias int) {
+	if rl == nil {
+		return
+	}
+	if alias >= 0 {
+		rl.SetPrompt(fmt.Sprintf("hb[%d]> ", alias))
+	} else {
+		rl.SetPrompt("hb> ")
+	}
+}
+
+func (r ttyRenderer) Event(name string, data json.RawMessage) {
+	pretty, _ := json.MarshalIndent(data, "  ", "  ")
+	out("%s[%s]%s %s", cYellow, name, cReset, string(pretty))
+}
+
+func (r ttyRenderer) Response(seq uint64, action string, result json.RawMessage, errStr string) {
+	if errStr != "" {
+		out("%serror:%s %s", cRed, cReset, errStr)
+		return
+	}
+
+	// Screenshot: save to file instead of dumping base64
+	if action == "tabs.screenshot" {
+		var obj map[string]interface{}
+		if json.Unmarshal(result, &obj) == nil {
+			if dataUrl, ok := obj["dataUrl"].(string); ok {
+				name, n, err := saveScreenshot(dataUrl)
+				if err != nil {
+					out("%serror:%s %v", cRed, cReset, err)
+				} else {
+					out("%sscreenshot:%s %s (%d bytes)", cGreen, cReset, name, n)
+				}
+				return
+			}
+		}
+	}
+
+	// tabs.list: formatted table with short aliases
+	if action == "tabs.list" {
+		var tabs []tabEntry
+		if json.Unmarshal(result, &tabs) == nil && len(tabs) > 0 {
+			updateTabMap(tabs)
+			for i, t := range tabs {
+				title := t.Title
+				if len(title) > 50 {
+					title = title[:47] + "..."
+				}
+				selected := " "
+				if int64(t.ID) == atomic.LoadInt64(&activeTab) {
+					selected = cGreen + ">" + cReset
+				}
+				out("%s %s%d%s  %s%d%s  %s  %s%s%s",
+					selected, cBold, i, cReset,
+					cDim, t.ID, cReset,
+					t.URL,
+					cDim, title, cReset)
+			}
+			out("%s  .tab <0-%d> to target a tab%s", cDim, len(tabs)-1, cReset)
+			return
+		}
+	}
+
+	// dom.discoverElements: formatted element list
+	if action == "dom.discoverElements" {
+		var disc struct {
+			Elements []struct {
+				Type        string `json:"type"`
+				Tag         string `json:"tag"`
+				Text        string `json:"text"`
+				Href        string `json:"href"`
+				HandleId    string `json:"handleId"`
+				Selector    string `json:"selector"`
+				InputType   string `json:"inputType"`
+				Name        string `json:"name"`
+				Placeholder string `json:"placeholder"`
+			} `json:"elements"`
+		}
+		if json.Unmarshal(result, &disc) == nil && len(disc.Elements) > 0 {
+			links, buttons, inputs := 0, 0, 0
+			for _, el := range disc.Elements {
+				switch el.Type {
+				case "link":
+					links++
+				case "button":
+					buttons++
+				case "input":
+					inputs++
+				}
+			}
+			out("%s%d elements%s  %s(%d links, %d buttons, %d inputs)%s",
+				cBold, len(disc.Elements), cReset, cDim, links, buttons, inputs, cReset)
+			out("")
+			for _, el := range disc.Elements {
+				label := el.Text
+				if len(label) > 50 {
+					label = label[:47] + "..."
+				}
+				switch el.Type {
+				case "link":
+					href := el.Href
+					if len(href) > 60 {
+						href = href[:57] + "..."
+					}
+					out("  %s%s%s  %s[link]%s  %s\"%s\"%s  %s→ %s%s",
+						cGreen, el.HandleId, cReset, cYellow, cReset,
+						cDim, label, cReset, cDim, href, cReset)
+				case "button":
+					out("  %s%s%s  %s[btn]%s   %s\"%s\"%s  %s%s%s",
+						cGreen, el.HandleId, cReset, cYellow, cReset,
+						cDim, label, cReset, cDim, el.Selector, cReset)
+				case "input":
+					desc := el.InputType
+					if el.Name != "" {
+						desc += " name=" + el.Name
+					}
+					if el.Placeholder != "" {
+						desc += " \"" + el.Placeholder + "\""
+					}
+					out("  %s%s%s  %s[input]%s %s%s%s  %s%s%s",
+						cGreen, el.HandleId, cReset, cYellow, cReset,
+						cDim, desc, cReset, cDim, el.Selector, cReset)
+				}
+			}
+			return
+		}
+	}
+
+	// Default: pretty-print JSON
+	var v interface{}
+	json.Unmarshal(result, &v)
+	pretty, _ := json.MarshalIndent(v, "", "  ")
+	out("%s", string(pretty))
+}
+
+// --- JSON renderer: machine-readable envelopes for --format json|ndjson ---
+
+type jsonRenderer struct {
+	compact bool // ndjson: one compact line per object; json: each object indented over several lines
+}
+
+var ansiRe = regexp.MustCompile(`\033\[[0-9;]*m`)
+
+func (j jsonRenderer) emit(v interface{}) {
+	var data []byte
+	if j.compact {
+		data, _ = json.Marshal(v)
+	} else {
+		data, _ = json.MarshalIndent(v, "", "  ")
+	}
+	fmt.Fprintln(os.Stdout, string(data))
+}
+
+func (j jsonRenderer) Info(format string, args ...interface{}) {
+	msg := ansiRe.ReplaceAllString(fmt.Sprintf(format, args...), "")
+	if msg == "" {
+		return
+	}
+	j.emit(map[string]interface{}{"info": msg})
+}
+
+func (j jsonRenderer) Prompt(alias int) {
+	// No interactive prompt in machine-readable mode.
+}
+
+func (j jsonRenderer) Event(name string, data json.RawMessage) {
+	j.emit(map[string]interface{}{"event": name, "data": data})
+}
+
+func (j jsonRenderer) Response(seq uint64, action string, result json.RawMessage, errStr string) {
+	envelope := map[string]interface{}{
+		"seq":    seq,
+		"id":     fmt.Sprintf("hb_%d", seq),
+		"action": action,
+		"ok":     errStr == "",
+	}
+	if errStr != "" {
+		envelope["error"] = errStr
+		j.emit(envelope)
+		return
+	}
+
+	if action == "tabs.screenshot" {
+		var obj map[string]interface{}
+		if json.Unmarshal(result, &obj) == nil {
+			if dataUrl, ok := obj["dataUrl"].(string); ok {
+				name, n, err := saveScreenshot(dataUrl)
+				if err != nil {
+					envelope["ok"] = false
+					envelope["error"] = err.Error()
+				} else {
+					envelope["result"] = map[string]interface{}{"file": name, "bytes": n}
+				}
+				j.emit(envelope)
+				return
+			}
+		}
+	}
+
+	envelope["result"] = result
+	j.emit(envelope)
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// --- record/play: lightweight automation on top of the REPL ---
+//
+// .record <file>  captures every dispatched line (shorthand or raw) until
+// .stop is issued. The file is written as a JSON array if it ends in
+// .json, otherwise as one command per line (a "playbook").
+//
+// .play <file> [--loop N] [--delay Xms] replays a playbook, substituting
+// ${env:NAME} and ${result:<action>.<path>} references against the last
+// seen result for that action before dispatching each line.
+
+var (
+	recording   int32
+	recordPath  string
+	recordMu    sync.Mutex
+	recordLines []string
+
+	lastResults sync.Map // action -> json.RawMessage
+)
+
+func startRecording(args string) {
+	parts := strings.Fields(args)
+	if len(parts) < 2 {
+		out("%susage: .record <file>%s", cDim, cReset)
+		return
+	}
+	recordMu.Lock()
+	recordPath = parts[1]
+	recordLines = nil
+	recordMu.Unlock()
+	atomic.StoreInt32(&recording, 1)
+	out("%srecording%s to %s", cGreen, cReset, recordPath)
+}
+
+func stopRecording() {
+	if atomic.SwapInt32(&recording, 0) == 0 {
+		out("%snot recording%s", cDim, cReset)
+		return
+	}
+	recordMu.Lock()
+	path, lines := recordPath, recordLines
+	recordMu.Unlock()
+
+	var data []byte
+	var err error
+	if strings.HasSuffix(path, ".json") {
+		data, err = json.MarshalIndent(lines, "", "  ")
+	} else {
+		data = []byte(strings.Join(lines, "\n") + "\n")
+	}
+	if err != nil {
+		out("%serror:%s %v", cRed, cReset, err)
+		return
+	}
+	if err := os.WriteFile(path, data, 0644); err != nil {
+		out("%serror:%s %v", cRed, cReset, err)
+		return
+	}
+	out("%ssaved%s %d step(s) to %s", cGreen, cReset, len(lines), path)
+}
+
+// recordLine appends a dispatched line to the in-progress recording, if any.
+func recordLine(line string) {
+	if atomic.LoadInt32(&recording) == 0 {
+		return
+	}
+	recordMu.Lock()
+	recordLines = append(recordLines, line)
+	recordMu.Unlock()
+}
+
+func loadPlaybook(path string) ([]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if strings.HasSuffix(path, ".json") {
+		var lines []string
+		if err := json.Unmarshal(data, &lines); err != nil {
+			return nil, fmt.Errorf("invalid playbook JSON: %w", err)
+		}
+		return lines, nil
+	}
+	var lines []string
+	scanner := bufio.NewScanner(strings.NewReader(string(data)))
+	for scanner.Scan() {
+		l := strings.TrimSpace(scanner.Text())
+		if l == "" || strings.HasPrefix(l, "#") {
+			continue
+		}
+		lines = append(lines, l)
+	}
+	return lines, nil
+}
+
+// playScript parses ".play <file> [--loop N] [--delay Xms] [--continue-on-error]"
+// and replays the recorded lines against dispatch.
+func playScript(args string) {
+	parts := strings.Fields(args)
+	if len(parts) < 2 {
+		out("%susage: .play <file> [--loop N] [--delay Xms] [--continue-on-error]%s", cDim, cReset)
+		return
+	}
+	path := parts[1]
+	loop := 1
+	delay := time.Duration(0)
+	continueOnError := false
+
+	for i := 2; i < len(parts); i++ {
+		switch {
+		case parts[i] == "--loop" && i+1 < len(parts):
+			i++
+			n, err := strconv.Atoi(parts[i])
+			if err == nil && n > 0 {
+				loop = n
+			}
+		case parts[i] == "--delay" && i+1 < len(parts):
+			i++
+			d, err := time.ParseDuration(parts[i])
+			if err == nil {
+				delay = d
+			}
+		case parts[i] == "--continue-on-error":
+			continueOnError = true
+		}
+	}
+
+	lines, err := loadPlaybook(path)
+	if err != nil {
+		out("%serror:%s %v", cRed, cReset, err)
+		return
+	}
+
+	for iter := 0; iter < loop; iter++ {
+		for i, raw := range lines {
+			line := substituteVars(raw)
+			out("%s-> play[%d/%d] %s%s", cDim, i+1, len(lines), line, cReset)
+			if err := dispatchChecked(line); err != nil && !continueOnError {
+				out("%splay aborted:%s %v", cRed, cReset, err)
+				return
+			}
+			if delay > 0 {
+				time.Sleep(delay)
+			}
+		}
+	}
+}
+
+var varRe = regexp.MustCompile(`\$\{(env|result):([^}]+)\}`)
+
+// substituteVars expands ${env:NAME} and ${result:<action>.<path>} references.
+func substituteVars(line string) string {
+	return varRe.ReplaceAllStringFunc(line, func(m string) string {
+		sub := varRe.FindStringSubmatch(m)
+		kind, expr := sub[1], sub[2]
+		switch kind {
+		case "env":
+			return os.Getenv(expr)
+		case "result":
+			return lookupResult(expr)
+		}
+		return m
+	})
+}
+
+// lookupResult walks a dotted path (action.field.0.field) against the last
+// recorded result for that action. Action names contain dots themselves
+// (tabs.list), so the longest leading run of segments that names a recorded
+// action is taken as the action and the rest as the path.
+func lookupResult(expr string) string {
+	parts := strings.Split(expr, ".")
+	var raw interface{}
+	n := 0
+	for i := len(parts); i > 0; i-- {
+		if r, ok := lastResults.Load(strings.Join(parts[:i], ".")); ok {
+			raw, n = r, i
+			break
+		}
+	}
+	if raw == nil {
+		return ""
+	}
+	var v interface{}
+	if err := json.Unmarshal(raw.(json.RawMessage), &v); err != nil {
+		return ""
+	}
+	for _, p := range parts[n:] {
+		switch t := v.(type) {
+		case map[string]interface{}:
+			v = t[p]
+		case []interface{}:
+			idx, err := strconv.Atoi(p)
+			if err != nil || idx < 0 || idx >= len(t) {
+				return ""
+			}
+			v = t[idx]
+		default:
+			return ""
+		}
+	}
+	if s, ok := v.(string); ok {
+		return s
+	}
+	b, _ := json.Marshal(v)
+	return string(b)
+}
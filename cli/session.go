@@ -0,0 +1,182 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+)
+
+// Session recording/replay operates one layer below the subscriptions/
+// scripting features above: it captures every raw frame that crosses the
+// Transport, in either direction, so a bug report can ship as a single
+// session.jsonl and a maintainer can `hb --replay` it without the original
+// server. This is deliberately a different mechanism from .record/.play
+// (script.go), which captures dispatched REPL lines rather than wire
+// frames — the two compose (you can --record while using .play).
+
+type sessionFrame struct {
+	Dir     string          `json:"dir"` // "send" or "recv"
+	Ts      time.Time       `json:"ts"`
+	Payload json.RawMessage `json:"payload"`
+}
+
+// --- --record: wrap a Transport and log every frame to disk ---
+
+type recordingTransport struct {
+	inner Transport
+	mu    sync.Mutex
+	w     *bufio.Writer
+	f     *os.File
+}
+
+func newRecordingTransport(inner Transport, path string) (*recordingTransport, error) {
+	f, err := os.Create(path)
+	if err != nil {
+		return nil, err
+	}
+	return &recordingTransport{inner: inner, w: bufio.NewWriter(f), f: f}, nil
+}
+
+func (r *recordingTransport) logFrame(dir string, payload []byte) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	line, err := json.Marshal(sessionFrame{Dir: dir, Ts: time.Now(), Payload: json.RawMessage(payload)})
+	if err != nil {
+		return
+	}
+	r.w.Write(line)
+	r.w.WriteByte('\n')
+	r.w.Flush()
+}
+
+func (r *recordingTransport) ReadMessage() ([]byte, error) {
+	msg, err := r.inner.ReadMessage()
+	if err == nil {
+		r.logFrame("recv", msg)
+	}
+	return msg, err
+}
+
+func (r *recordingTransport) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	r.logFrame("send", data)
+	return r.inner.WriteJSON(v)
+}
+
+func (r *recordingTransport) Close() error {
+	r.mu.Lock()
+	r.w.Flush()
+	r.mu.Unlock()
+	r.f.Close()
+	return r.inner.Close()
+}
+
+// --- --replay: load a session log ---
+
+func loadSessionLog(path string) ([]sessionFrame, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var frames []sessionFrame
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 4*1024*1024)
+	for scanner.Scan() {
+		line := scanner.Bytes()
+		if len(line) == 0 {
+			continue
+		}
+		var fr sessionFrame
+		if err := json.Unmarshal(line, &fr); err != nil {
+			return nil, fmt.Errorf("parsing %s: %w", path, err)
+		}
+		frames = append(frames, fr)
+	}
+	return frames, scanner.Err()
+}
+
+// mockTransport answers live WriteJSON calls with whatever the recorded
+// session returned after the correspondingly-positioned send, so a replayed
+// client that issues the same commands in the same order gets the same
+// responses without a real server. Positional matching (rather than by id)
+// is deliberate: ids are client-assigned sequence numbers that a replay run
+// regenerates independently of the original recording.
+type mockTransport struct {
+	frames []sessionFrame
+	pos    int // index into frames of the next unconsumed send
+
+	mu      sync.Mutex
+	pending []json.RawMessage
+}
+
+func newMockTransport(frames []sessionFrame) *mockTransport {
+	return &mockTransport{frames: frames}
+}
+
+func (m *mockTransport) WriteJSON(v interface{}) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	// Find the next recorded "send", then collect every "recv" up to the
+	// following "send" (or end of log) as this call's replayed responses.
+	for m.pos < len(m.frames) && m.frames[m.pos].Dir != "send" {
+		m.pos++
+	}
+	if m.pos >= len(m.frames) {
+		return nil // recording exhausted; ReadMessage reports io.EOF once pending drains
+	}
+	m.pos++ // consume the send itself
+
+	for m.pos < len(m.frames) && m.frames[m.pos].Dir == "recv" {
+		m.pending = append(m.pending, m.frames[m.pos].Payload)
+		m.pos++
+	}
+	return nil
+}
+
+func (m *mockTransport) ReadMessage() ([]byte, error) {
+	for {
+		m.mu.Lock()
+		if len(m.pending) > 0 {
+			next := m.pending[0]
+			m.pending = m.pending[1:]
+			m.mu.Unlock()
+			return next, nil
+		}
+		m.mu.Unlock()
+		if m.pos >= len(m.frames) {
+			return nil, io.EOF
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func (m *mockTransport) Close() error { return nil }
+
+// replaySendsAgainstLive feeds every recorded "send" payload to a live
+// connection in order, for the "reproduce against a real server" replay
+// mode, and reports how many were sent.
+func replaySendsAgainstLive(frames []sessionFrame) int {
+	sent := 0
+	for _, fr := range frames {
+		if fr.Dir != "send" {
+			continue
+		}
+		var msg map[string]interface{}
+		if json.Unmarshal(fr.Payload, &msg) != nil {
+			continue
+		}
+		wsSend(msg)
+		sent++
+	}
+	return sent
+}
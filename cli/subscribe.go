@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/hugopalma17/human-browser/cli/subscriptions"
+)
+
+// sub/unsub/subs wrap the subscriptions package for the REPL: a small
+// filter-expression language over event payloads, persisted to
+// ~/.hb_subs.json so they re-arm on the next run or after a reconnect
+// (reconnect doesn't need to do anything special — the Manager just keeps
+// matching events as they flow back in over the new connection).
+
+var subMgr *subscriptions.Manager
+
+func initSubscriptions() {
+	home, _ := os.UserHomeDir()
+	subMgr = subscriptions.NewManager(filepath.Join(home, ".hb_subs.json"))
+	if err := subMgr.Load(); err != nil {
+		fmt.Fprintf(os.Stderr, "%swarning: loading ~/.hb_subs.json: %v%s\n", cDim, err, cReset)
+	}
+}
+
+func subscribeCmd(rest string) {
+	parts := strings.SplitN(rest, " ", 2)
+	topic := parts[0]
+	filterExpr := ""
+	if len(parts) == 2 {
+		filterExpr = strings.TrimSpace(parts[1])
+	}
+	sub, err := subMgr.Subscribe(topic, filterExpr)
+	if err != nil {
+		out("%serror:%s %v", cRed, cReset, err)
+		return
+	}
+	out("%s%s%s  %s", cGreen, sub.ID, cReset, sub.Topic)
+}
+
+func unsubscribeCmd(id string) {
+	if subMgr.Unsubscribe(id) {
+		out("unsubscribed %s", id)
+	} else {
+		out("%sunknown subscription:%s %s", cRed, cReset, id)
+	}
+}
+
+func listSubscriptions() {
+	subs := subMgr.List()
+	if len(subs) == 0 {
+		out("%s(no active subscriptions)%s", cDim, cReset)
+		return
+	}
+	for _, s := range subs {
+		if s.FilterExpr != "" {
+			out("%s%s%s  %s  %s(%s)%s", cGreen, s.ID, cReset, s.Topic, cDim, s.FilterExpr, cReset)
+		} else {
+			out("%s%s%s  %s", cGreen, s.ID, cReset, s.Topic)
+		}
+	}
+}
+
+// dispatchSubscriptions prints every subscription matching an incoming
+// event, and feeds a --watch wait if one is pending.
+func dispatchSubscriptions(topic string, data json.RawMessage) {
+	for _, s := range subMgr.Match(topic, data) {
+		if watchCh != nil && s.ID == watchSubID {
+			select {
+			case watchCh <- data:
+			default:
+			}
+			continue
+		}
+		out("%s[%s]%s %s", cYellow, s.ID, cReset, string(data))
+	}
+}
+
+// --- --watch: oneshot wait for a single matching event ---
+
+var (
+	watchSubID string
+	watchCh    chan json.RawMessage
+)
+
+// runWatch subscribes to "topic [filter]" (space-separated, same grammar as
+// `sub`), waits for the first matching event, prints its payload, and exits.
+func runWatch(arg string) {
+	parts := strings.SplitN(strings.TrimSpace(arg), " ", 2)
+	topic := parts[0]
+	filterExpr := ""
+	if len(parts) == 2 {
+		filterExpr = strings.TrimSpace(parts[1])
+	}
+
+	sub, err := subMgr.Subscribe(topic, filterExpr)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		os.Exit(1)
+	}
+	watchSubID = sub.ID
+	watchCh = make(chan json.RawMessage, 1)
+
+	select {
+	case data := <-watchCh:
+		fmt.Println(string(data))
+		subMgr.Unsubscribe(sub.ID)
+		getConn().Close()
+		os.Exit(0)
+	case <-time.After(cmdTimeout):
+		subMgr.Unsubscribe(sub.ID)
+		fmt.Fprintf(os.Stderr, "timed out waiting for %s\n", topic)
+		getConn().Close()
+		os.Exit(1)
+	}
+}
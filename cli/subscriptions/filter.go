@@ -0,0 +1,131 @@
+package subscriptions
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// filter is a single predicate evaluated against an event's JSON payload.
+type filter interface {
+	match(payload map[string]interface{}) bool
+}
+
+// parseFilter accepts small predicates of the form `field op value`, e.g.
+//
+//	url ~ "example.com"
+//	statusCode >= 400
+//
+// An empty expression always matches. ~ does a substring test; == and !=
+// compare either numbers or strings, while >, >=, < and <= compare numbers
+// only and never match when either side is not a number. The operator
+// is anchored on the first one found outside quotes, so a quoted value
+// containing operator-like text (e.g. `url ~ "...?token=abc=="`) doesn't get
+// mistaken for the filter's own operator.
+func parseFilter(expr string) (filter, error) {
+	expr = strings.TrimSpace(expr)
+	if expr == "" {
+		return nil, nil
+	}
+
+	ops := []string{">=", "<=", "!=", "==", ">", "<", "~"}
+	var inQuote byte
+	for i := 0; i < len(expr); i++ {
+		c := expr[i]
+		if inQuote != 0 {
+			if c == inQuote {
+				inQuote = 0
+			}
+			continue
+		}
+		if c == '"' || c == '\'' {
+			inQuote = c
+			continue
+		}
+		for _, op := range ops {
+			if !strings.HasPrefix(expr[i:], op) {
+				continue
+			}
+			field := strings.TrimSpace(expr[:i])
+			rawValue := strings.TrimSpace(expr[i+len(op):])
+			if field == "" || rawValue == "" {
+				continue
+			}
+			return &fieldFilter{field: field, op: op, value: parseFilterValue(rawValue)}, nil
+		}
+	}
+
+	return nil, fmt.Errorf("unrecognized filter expression %q", expr)
+}
+
+func parseFilterValue(raw string) interface{} {
+	if len(raw) >= 2 && (raw[0] == '"' || raw[0] == '\'') && raw[len(raw)-1] == raw[0] {
+		return raw[1 : len(raw)-1]
+	}
+	if n, err := strconv.ParseFloat(raw, 64); err == nil {
+		return n
+	}
+	return raw
+}
+
+type fieldFilter struct {
+	field string
+	op    string
+	value interface{}
+}
+
+func (f *fieldFilter) match(payload map[string]interface{}) bool {
+	got, ok := payload[f.field]
+	if !ok {
+		return false
+	}
+
+	if f.op == "~" {
+		gotStr, ok := got.(string)
+		if !ok {
+			return false
+		}
+		wantStr, _ := f.value.(string)
+		return strings.Contains(gotStr, wantStr)
+	}
+
+	gotNum, gotIsNum := toFloat(got)
+	wantNum, wantIsNum := toFloat(f.value)
+	if gotIsNum && wantIsNum {
+		switch f.op {
+		case "==":
+			return gotNum == wantNum
+		case "!=":
+			return gotNum != wantNum
+		case ">":
+			return gotNum > wantNum
+		case ">=":
+			return gotNum >= wantNum
+		case "<":
+			return gotNum < wantNum
+		case "<=":
+			return gotNum <= wantNum
+		}
+	}
+
+	gotStr := fmt.Sprintf("%v", got)
+	wantStr := fmt.Sprintf("%v", f.value)
+	switch f.op {
+	case "==":
+		return gotStr == wantStr
+	case "!=":
+		return gotStr != wantStr
+	default:
+		return false
+	}
+}
+
+func toFloat(v interface{}) (float64, bool) {
+	switch n := v.(type) {
+	case float64:
+		return n, true
+	case int:
+		return float64(n), true
+	}
+	return 0, false
+}
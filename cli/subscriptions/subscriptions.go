@@ -0,0 +1,153 @@
+// Package subscriptions implements the client-side event subscription
+// registry: topics, filter predicates, and persistence across reconnects.
+// The CLI owns the WebSocket connection and event loop; this package only
+// tracks *what* the user asked to be notified about and decides whether a
+// given event payload matches.
+package subscriptions
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+)
+
+// Subscription is one `sub <topic> [filter-expr]` registration.
+type Subscription struct {
+	ID         string `json:"id"`
+	Topic      string `json:"topic"`
+	FilterExpr string `json:"filter,omitempty"`
+	filter     filter
+}
+
+// Manager owns the id->Subscription map and its on-disk persistence.
+type Manager struct {
+	mu     sync.Mutex
+	path   string
+	nextID int
+	subs   map[string]*Subscription
+}
+
+// NewManager creates a Manager backed by path (typically ~/.hb_subs.json).
+// The file isn't read until Load is called.
+func NewManager(path string) *Manager {
+	return &Manager{path: path, subs: map[string]*Subscription{}}
+}
+
+// Subscribe registers a new subscription and persists the updated set.
+func (m *Manager) Subscribe(topic, filterExpr string) (*Subscription, error) {
+	f, err := parseFilter(filterExpr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid filter: %w", err)
+	}
+
+	m.mu.Lock()
+	m.nextID++
+	sub := &Subscription{
+		ID:         fmt.Sprintf("sub_%d", m.nextID),
+		Topic:      topic,
+		FilterExpr: filterExpr,
+		filter:     f,
+	}
+	m.subs[sub.ID] = sub
+	m.mu.Unlock()
+
+	return sub, m.save()
+}
+
+// Unsubscribe removes a subscription by id, reporting whether it existed.
+func (m *Manager) Unsubscribe(id string) bool {
+	m.mu.Lock()
+	_, ok := m.subs[id]
+	delete(m.subs, id)
+	m.mu.Unlock()
+	if ok {
+		m.save()
+	}
+	return ok
+}
+
+// List returns all active subscriptions, sorted by id.
+func (m *Manager) List() []*Subscription {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	out := make([]*Subscription, 0, len(m.subs))
+	for _, s := range m.subs {
+		out = append(out, s)
+	}
+	sort.Slice(out, func(i, j int) bool { return out[i].ID < out[j].ID })
+	return out
+}
+
+// Match returns the subscriptions whose topic equals topic and whose filter
+// (if any) accepts data.
+func (m *Manager) Match(topic string, data json.RawMessage) []*Subscription {
+	var payload map[string]interface{}
+	json.Unmarshal(data, &payload)
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	var matched []*Subscription
+	for _, s := range m.subs {
+		if s.Topic != topic {
+			continue
+		}
+		if s.filter == nil || s.filter.match(payload) {
+			matched = append(matched, s)
+		}
+	}
+	return matched
+}
+
+// persistedFile is the on-disk shape; it drops the compiled filter.
+type persistedFile struct {
+	Subs   []*Subscription `json:"subs"`
+	NextID int             `json:"nextId"`
+}
+
+func (m *Manager) save() error {
+	m.mu.Lock()
+	pf := persistedFile{NextID: m.nextID}
+	for _, s := range m.subs {
+		pf.Subs = append(pf.Subs, s)
+	}
+	m.mu.Unlock()
+
+	data, err := json.MarshalIndent(pf, "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(m.path, data, 0o600)
+}
+
+// Load reads previously persisted subscriptions so they re-arm after a
+// restart or reconnect without the user having to retype them. A missing
+// file is not an error.
+func (m *Manager) Load() error {
+	data, err := os.ReadFile(m.path)
+	if os.IsNotExist(err) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	var pf persistedFile
+	if err := json.Unmarshal(data, &pf); err != nil {
+		return fmt.Errorf("parsing %s: %w", m.path, err)
+	}
+
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID = pf.NextID
+	for _, s := range pf.Subs {
+		f, err := parseFilter(s.FilterExpr)
+		if err != nil {
+			continue // drop subscriptions whose filter no longer parses
+		}
+		s.filter = f
+		m.subs[s.ID] = s
+	}
+	return nil
+}
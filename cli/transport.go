@@ -0,0 +1,258 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+	"sync"
+
+	"github.com/gorilla/websocket"
+	"golang.org/x/net/http2"
+)
+
+// Transport is the one thing that knows how to move a message to and from
+// the server. -addr picks an implementation by scheme: ws(s):// is today's
+// gorilla WebSocket, h2:// tunnels the same JSON messages over a single
+// Extended CONNECT (RFC 8441) HTTP/2 stream, and unix:// talks to a local
+// server over a domain socket. Everything above this layer — dispatch,
+// reconnect, replay — only ever sees Transport.
+type Transport interface {
+	ReadMessage() ([]byte, error)
+	WriteJSON(v interface{}) error
+	Close() error
+}
+
+var (
+	tlsInsecure   bool   // --insecure
+	tlsCAFile     string // --cafile
+	tlsClientCert string // --client-cert: PEM file with both cert and key
+)
+
+func buildTLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{InsecureSkipVerify: tlsInsecure}
+
+	if tlsCAFile != "" {
+		data, err := os.ReadFile(tlsCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("reading --cafile: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(data) {
+			return nil, fmt.Errorf("--cafile %s: no certificates found", tlsCAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	if tlsClientCert != "" {
+		cert, err := loadClientCert(tlsClientCert)
+		if err != nil {
+			return nil, fmt.Errorf("reading --client-cert: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	return cfg, nil
+}
+
+// loadClientCert reads a single PEM file holding both the client certificate
+// and its private key, which is the common shape for dev mTLS setups.
+func loadClientCert(path string) (tls.Certificate, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	var certPEM, keyPEM []byte
+	rest := data
+	for {
+		var block *pem.Block
+		block, rest = pem.Decode(rest)
+		if block == nil {
+			break
+		}
+		if strings.Contains(block.Type, "CERTIFICATE") {
+			certPEM = append(certPEM, pem.EncodeToMemory(block)...)
+		} else {
+			keyPEM = append(keyPEM, pem.EncodeToMemory(block)...)
+		}
+	}
+	return tls.X509KeyPair(certPEM, keyPEM)
+}
+
+// dialTransport picks an implementation by the scheme of addr and connects.
+func dialTransport(addr string) (Transport, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, fmt.Errorf("invalid -addr %q: %w", addr, err)
+	}
+
+	switch u.Scheme {
+	case "ws", "wss":
+		return dialWS(addr)
+	case "h2":
+		return dialH2(addr)
+	case "unix":
+		return dialUnix(u)
+	default:
+		return nil, fmt.Errorf("unsupported -addr scheme %q (want ws, wss, h2, or unix)", u.Scheme)
+	}
+}
+
+// --- gorilla WebSocket transport (ws://, wss://) ---
+
+type wsTransport struct {
+	conn *websocket.Conn
+}
+
+func dialWS(addr string) (Transport, error) {
+	tlsConfig, err := buildTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	dialer := *websocket.DefaultDialer
+	dialer.TLSClientConfig = tlsConfig
+	applyCompression(&dialer)
+
+	c, resp, err := dialer.Dial(addr, nil)
+	if err != nil {
+		return nil, err
+	}
+	if resp != nil {
+		finishCompressionNegotiation(c, resp.Header.Get("Sec-WebSocket-Extensions"))
+	}
+	return &wsTransport{conn: c}, nil
+}
+
+func (w *wsTransport) ReadMessage() ([]byte, error) {
+	_, msg, err := w.conn.ReadMessage()
+	return msg, err
+}
+
+func (w *wsTransport) WriteJSON(v interface{}) error { return w.conn.WriteJSON(v) }
+func (w *wsTransport) Close() error                  { return w.conn.Close() }
+
+// --- shared newline-delimited JSON framing for the non-WS transports ---
+
+// streamTransport frames the same JSON messages dispatch already sends as
+// one object per line over a plain duplex byte stream. Neither the Unix
+// socket nor the H2 tunnel need WebSocket's own frame format — they just
+// need a reliable ordered byte pipe, which is what they give us.
+type streamTransport struct {
+	rw io.ReadWriteCloser
+	r  *bufio.Reader
+	mu sync.Mutex
+}
+
+func newStreamTransport(rw io.ReadWriteCloser) *streamTransport {
+	return &streamTransport{rw: rw, r: bufio.NewReader(rw)}
+}
+
+func (s *streamTransport) ReadMessage() ([]byte, error) {
+	line, err := s.r.ReadBytes('\n')
+	if err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(line, "\n"), nil
+}
+
+func (s *streamTransport) WriteJSON(v interface{}) error {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	data = append(data, '\n')
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	_, err = s.rw.Write(data)
+	return err
+}
+
+func (s *streamTransport) Close() error { return s.rw.Close() }
+
+// --- Unix domain socket transport (unix:///path/to.sock) ---
+
+func dialUnix(u *url.URL) (Transport, error) {
+	path := u.Path
+	if path == "" {
+		path = u.Opaque
+	}
+	c, err := net.Dial("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	return newStreamTransport(c), nil
+}
+
+// --- WebSocket-over-HTTP/2 transport (h2://), RFC 8441 Extended CONNECT ---
+
+// h2Stream joins the request body we stream into with the response body we
+// read from into a single io.ReadWriteCloser, which is all streamTransport
+// needs to treat the tunnel like any other duplex connection.
+type h2Stream struct {
+	reader io.ReadCloser
+	writer io.WriteCloser
+}
+
+func (h *h2Stream) Read(p []byte) (int, error)  { return h.reader.Read(p) }
+func (h *h2Stream) Write(p []byte) (int, error) { return h.writer.Write(p) }
+func (h *h2Stream) Close() error {
+	werr := h.writer.Close()
+	rerr := h.reader.Close()
+	if werr != nil {
+		return werr
+	}
+	return rerr
+}
+
+func dialH2(addr string) (Transport, error) {
+	u, err := url.Parse(addr)
+	if err != nil {
+		return nil, err
+	}
+	tlsConfig, err := buildTLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	cfg := tlsConfig.Clone()
+	cfg.NextProtos = []string{"h2"}
+
+	raw, err := tls.Dial("tcp", u.Host, cfg)
+	if err != nil {
+		return nil, err
+	}
+
+	t := &http2.Transport{}
+	cc, err := t.NewClientConn(raw)
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+
+	pr, pw := io.Pipe()
+	req := &http.Request{
+		Method: http.MethodConnect,
+		URL:    &url.URL{Scheme: "https", Host: u.Host, Path: u.Path},
+		Header: http.Header{":protocol": []string{"websocket"}},
+		Body:   pr,
+	}
+	resp, err := cc.RoundTrip(req)
+	if err != nil {
+		raw.Close()
+		return nil, err
+	}
+	if resp.StatusCode != http.StatusOK {
+		raw.Close()
+		return nil, fmt.Errorf("h2 extended connect to %s: unexpected status %s", addr, resp.Status)
+	}
+
+	return newStreamTransport(&h2Stream{reader: resp.Body, writer: pw}), nil
+}